@@ -0,0 +1,354 @@
+/*
+Package contract instruments a tigertonic Marshaled handler's request/response
+cycles and turns them into consumer-driven contract tests.
+
+Record wraps a mux (typically a *tigertonic.TrieServeMux) during a consumer's
+test run and, when that test finishes, writes every request/response pair it
+saw as a Pact-compatible (https://docs.pact.io/) JSON file. Verify later
+replays a directory of such files against a live mux — the provider's mux —
+and reports every interaction whose status or response body shape no longer
+matches what the consumer recorded.
+
+Bodies are compared structurally rather than byte-for-byte: Verify checks
+that the provider's response has the same JSON shape (same object keys, same
+scalar types) as was recorded, not that it returns identical values, since
+contract tests should survive a provider returning different but
+equally-valid data.
+*/
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Interaction is one recorded request/response cycle.
+type Interaction struct {
+	Description string         `json:"description"`
+	Request     RequestSchema  `json:"request"`
+	Response    ResponseSchema `json:"response"`
+}
+
+// RequestSchema describes the request side of a recorded Interaction. Body
+// holds the literal recorded request body, verbatim, so Verify can replay
+// it; BodySchema is derived from Body and recorded alongside it. Verify
+// replays Body and does not compare request shapes; only the response's
+// BodySchema is checked.
+type RequestSchema struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Pattern    string            `json:"pattern,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodySchema interface{}       `json:"bodySchema,omitempty"`
+}
+
+// ResponseSchema describes the response side of a recorded Interaction. The
+// ErrorType/ErrorCode fields, when non-empty, come from a
+// tigertonic.AppError-shaped response body.
+type ResponseSchema struct {
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	BodySchema interface{}       `json:"bodySchema,omitempty"`
+	ErrorType  string            `json:"errorType,omitempty"`
+	ErrorCode  int               `json:"errorCode,omitempty"`
+}
+
+// pact is the document Record writes and Verify reads, one file per
+// consumer test run.
+type pact struct {
+	Consumer     pactParty     `json:"consumer"`
+	Provider     pactParty     `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type pactParty struct {
+	Name string `json:"name"`
+}
+
+// routePatterner is implemented by muxes that can report which registered
+// pattern matched a given request. Record uses it, when available, to
+// populate RequestSchema.Pattern; a mux that doesn't implement it still
+// records everything else.
+type routePatterner interface {
+	MatchedPattern(*http.Request) (pattern string, ok bool)
+}
+
+// Record wraps mux so that every request/response cycle served through the
+// returned http.Handler during t is captured as an Interaction. When t
+// finishes, the interactions are written as a Pact-compatible JSON file
+// under contracts/<t.Name()>.json, with "/" and spaces in the test name
+// replaced by "_". Drive the returned handler the same way
+// you'd drive mux directly, e.g. with httptest.NewServer or by calling
+// ServeHTTP against an httptest.ResponseRecorder.
+func Record(t *testing.T, mux http.Handler) http.Handler {
+	rec := &recorder{mux: mux}
+	t.Cleanup(func() {
+		if err := rec.flush(t.Name()); nil != err {
+			t.Errorf("contract: writing recorded interactions: %s", err)
+		}
+	})
+	return rec
+}
+
+type recorder struct {
+	mux          http.Handler
+	interactions []Interaction
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if nil != r.Body {
+		body, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	rw := httptest.NewRecorder()
+	rec.mux.ServeHTTP(rw, r)
+
+	for k, v := range rw.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rw.Code)
+	w.Write(rw.Body.Bytes())
+
+	pattern := ""
+	if patterner, ok := rec.mux.(routePatterner); ok {
+		pattern, _ = patterner.MatchedPattern(r)
+	}
+
+	errType, errCode := appErrorFields(rw.Body.Bytes())
+
+	rec.interactions = append(rec.interactions, Interaction{
+		Description: fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+		Request: RequestSchema{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Pattern:    pattern,
+			Headers:    flattenHeader(r.Header),
+			Body:       string(body),
+			BodySchema: schemaOf(body),
+		},
+		Response: ResponseSchema{
+			Status:     rw.Code,
+			Headers:    flattenHeader(rw.Header()),
+			BodySchema: schemaOf(rw.Body.Bytes()),
+			ErrorType:  errType,
+			ErrorCode:  errCode,
+		},
+	})
+}
+
+func (rec *recorder) flush(name string) error {
+	if 0 == len(rec.interactions) {
+		return nil
+	}
+	if err := os.MkdirAll("contracts", 0755); nil != err {
+		return err
+	}
+
+	doc := pact{
+		Consumer:     pactParty{Name: name},
+		Provider:     pactParty{Name: "tigertonic"},
+		Interactions: rec.interactions,
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join("contracts", sanitizeFilename(name)+".json"), data, 0644)
+}
+
+// Verify replays every interaction recorded under dir against mux and
+// returns an error describing every interaction that no longer matches —
+// wrong status, or a response body whose shape no longer matches what was
+// recorded — rather than stopping at the first one. Wrap it in t.Fatal from
+// a provider's own test to turn it into a normal test failure.
+func Verify(dir string, mux http.Handler) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if nil != err {
+		return err
+	}
+
+	var problems []string
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if nil != err {
+			problems = append(problems, fmt.Sprintf("%s: %s", file, err))
+			continue
+		}
+		var doc pact
+		if err := json.Unmarshal(data, &doc); nil != err {
+			problems = append(problems, fmt.Sprintf("%s: %s", file, err))
+			continue
+		}
+		for _, interaction := range doc.Interactions {
+			if err := verifyInteraction(mux, interaction); nil != err {
+				problems = append(problems, fmt.Sprintf("%s: %s: %s", file, interaction.Description, err))
+			}
+		}
+	}
+
+	if 0 < len(problems) {
+		return fmt.Errorf("contract: %d interaction(s) failed verification:\n%s", len(problems), strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func verifyInteraction(mux http.Handler, interaction Interaction) error {
+	// http.NewRequest leaves Body nil for a nil io.Reader, but net/http
+	// guarantees a server-side Request.Body is always non-nil; replaying
+	// with a nil Body crashes handlers that read it the normal way, so an
+	// empty interaction still gets http.NoBody rather than nil.
+	var body io.Reader = http.NoBody
+	if "" != interaction.Request.Body {
+		body = strings.NewReader(interaction.Request.Body)
+	}
+
+	r, err := http.NewRequest(interaction.Request.Method, interaction.Request.Path, body)
+	if nil != err {
+		return err
+	}
+	for k, v := range interaction.Request.Headers {
+		r.Header.Set(k, v)
+	}
+
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, r)
+
+	if rw.Code != interaction.Response.Status {
+		return fmt.Errorf("expected status %d, got %d", interaction.Response.Status, rw.Code)
+	}
+
+	if nil != interaction.Response.BodySchema {
+		actual := schemaOf(rw.Body.Bytes())
+		if !schemaMatches(interaction.Response.BodySchema, actual) {
+			return fmt.Errorf("response body schema mismatch: expected %#v, got %#v", interaction.Response.BodySchema, actual)
+		}
+	}
+
+	return nil
+}
+
+// appErrorFields extracts the "type" and "code" members of a
+// tigertonic.AppError-shaped JSON body, if body is one, for
+// ResponseSchema's ErrorType/ErrorCode.
+func appErrorFields(body []byte) (errType string, errCode int) {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); nil != err {
+		return "", 0
+	}
+	if t, ok := decoded["type"].(string); ok {
+		errType = t
+	}
+	if c, ok := decoded["code"].(float64); ok {
+		errCode = int(c)
+	}
+	return errType, errCode
+}
+
+func flattenHeader(h http.Header) map[string]string {
+	if 0 == len(h) {
+		return nil
+	}
+	flat := make(map[string]string, len(h))
+	for k, v := range h {
+		if 0 < len(v) {
+			flat[k] = v[0]
+		}
+	}
+	return flat
+}
+
+func sanitizeFilename(name string) string {
+	return strings.NewReplacer("/", "_", " ", "_").Replace(name)
+}
+
+// schemaOf derives a structural JSON schema from a response or request
+// body: maps and slices keep their shape, and scalars are replaced by a
+// string naming their JSON type, so two bodies with different values but
+// the same shape produce equal schemas.
+func schemaOf(body []byte) interface{} {
+	if 0 == len(bytes.TrimSpace(body)) {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); nil != err {
+		return "string"
+	}
+	return typeSchema(v)
+}
+
+func typeSchema(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		schema := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			schema[k] = typeSchema(vv)
+		}
+		return schema
+	case []interface{}:
+		if 0 == len(t) {
+			return []interface{}{}
+		}
+		return []interface{}{typeSchema(t[0])}
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", t)
+	}
+}
+
+// schemaMatches reports whether actual has the same shape as expected:
+// the same object keys (recursively matching), the same scalar type names,
+// and — for non-empty expected arrays — every element of actual matching
+// the expected element's schema.
+func schemaMatches(expected, actual interface{}) bool {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok || len(exp) != len(act) {
+			return false
+		}
+		for k, ev := range exp {
+			av, ok := act[k]
+			if !ok || !schemaMatches(ev, av) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		if 0 == len(exp) {
+			return true
+		}
+		for _, av := range act {
+			if !schemaMatches(exp[0], av) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
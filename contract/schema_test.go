@@ -0,0 +1,27 @@
+package contract
+
+import "testing"
+
+func TestSchemaMatchesAcrossDifferentValues(t *testing.T) {
+	recorded := schemaOf([]byte(`{"id":1,"tags":["x","y"]}`))
+	live := schemaOf([]byte(`{"id":2,"tags":["z"]}`))
+	if !schemaMatches(recorded, live) {
+		t.Fatalf("expected schemas to match: %#v vs %#v", recorded, live)
+	}
+}
+
+func TestSchemaMismatchOnTypeChange(t *testing.T) {
+	recorded := schemaOf([]byte(`{"id":1}`))
+	live := schemaOf([]byte(`{"id":"not-a-number"}`))
+	if schemaMatches(recorded, live) {
+		t.Fatalf("expected schemas not to match: %#v vs %#v", recorded, live)
+	}
+}
+
+func TestSchemaMismatchOnMissingKey(t *testing.T) {
+	recorded := schemaOf([]byte(`{"id":1,"name":"a"}`))
+	live := schemaOf([]byte(`{"id":1}`))
+	if schemaMatches(recorded, live) {
+		t.Fatalf("expected schemas not to match: %#v vs %#v", recorded, live)
+	}
+}
@@ -0,0 +1,270 @@
+package tigertonic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	CSRFErrorType = "csrf"
+	CSRFErrorCode = 7000
+)
+
+// CSRFError is returned by CSRF-protected handlers when a request's token
+// is missing or fails validation. Its Name method satisfies NamedError, so
+// WriteJSONError and WritePlaintextError report it as "csrf_token_invalid"
+// rather than falling back to SnakeCaseHTTPEquivErrors or the bare Go type
+// name. WriteError's problem+json, problem+xml, and xml representations use
+// the embedded AppError's Problem method instead, so their type URI ends in
+// CSRFErrorType.
+type CSRFError struct {
+	AppError
+}
+
+func (CSRFError) Name() string {
+	return "csrf_token_invalid"
+}
+
+func newCSRFError(desc string) error {
+	return &CSRFError{AppError{
+		Type:           CSRFErrorType,
+		Code:           CSRFErrorCode,
+		Desc:           desc,
+		HttpStatusCode: http.StatusForbidden,
+	}}
+}
+
+// CSRFConfig configures the CSRF middleware. It implements a signed
+// double-submit cookie: on safe methods, a random token is HMAC-signed and
+// set as a cookie; on unsafe methods, the same token must come back in a
+// header or form field and match the cookie, so an attacker who can make
+// the browser send a cross-site request still cannot read or guess the
+// cookie's value. The HMAC guards against an attacker who can merely set
+// cookies on the origin (e.g. from a sibling subdomain) but can't read
+// them.
+type CSRFConfig struct {
+	// CookieName is the cookie the token is stored in. Defaults to "csrf_token".
+	CookieName string
+
+	// HeaderName is the request header an unsafe-method request may carry
+	// the token in. Defaults to "X-CSRF-Token".
+	HeaderName string
+
+	// FieldName is the form field an unsafe-method request may carry the
+	// token in instead, for plain HTML form posts. Defaults to "csrf_token".
+	FieldName string
+
+	// Secrets HMAC-sign and verify issued tokens. Secrets[0] signs newly
+	// issued tokens; every entry is accepted when verifying, so a secret
+	// can be rotated by prepending a new one and dropping the oldest once
+	// its cookies have expired. At least one secret is required.
+	Secrets [][]byte
+
+	// Path and Domain scope the cookie. Path defaults to "/"; Domain
+	// defaults to unset (host-only cookie).
+	Path, Domain string
+
+	// Secure, HTTPOnly, and SameSite set the corresponding cookie flags.
+	Secure   bool
+	HTTPOnly bool
+	SameSite http.SameSite
+
+	// MaxAge is the cookie lifetime. Defaults to 12 hours. Tokens themselves
+	// carry no expiry: a token signed by any of Secrets still verifies after
+	// its cookie has expired.
+	MaxAge time.Duration
+
+	// TrustedOrigins allowlists additional Origin header values, beyond the
+	// request's own Host (compared by host only, whatever the Origin's
+	// scheme), allowed to make unsafe cross-origin AJAX
+	// requests (e.g. a separate frontend origin in development). A request
+	// that carries no Origin header at all is not subject to this check,
+	// since the double-submit cookie comparison already blocks cross-site
+	// attackers who cannot read the cookie.
+	TrustedOrigins []string
+}
+
+func (c CSRFConfig) cookieName() string {
+	if "" != c.CookieName {
+		return c.CookieName
+	}
+	return "csrf_token"
+}
+
+func (c CSRFConfig) headerName() string {
+	if "" != c.HeaderName {
+		return c.HeaderName
+	}
+	return "X-CSRF-Token"
+}
+
+func (c CSRFConfig) fieldName() string {
+	if "" != c.FieldName {
+		return c.FieldName
+	}
+	return "csrf_token"
+}
+
+func (c CSRFConfig) path() string {
+	if "" != c.Path {
+		return c.Path
+	}
+	return "/"
+}
+
+func (c CSRFConfig) maxAge() time.Duration {
+	if 0 != c.MaxAge {
+		return c.MaxAge
+	}
+	return 12 * time.Hour
+}
+
+func (c CSRFConfig) signingSecret() []byte {
+	if 0 == len(c.Secrets) {
+		return nil
+	}
+	return c.Secrets[0]
+}
+
+func (c CSRFConfig) cookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     c.cookieName(),
+		Value:    token,
+		Path:     c.path(),
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
+		MaxAge:   int(c.maxAge() / time.Second),
+	}
+}
+
+// originTrusted reports whether r's Origin header, if any, belongs to r's
+// own host or to config's TrustedOrigins allowlist.
+func (c CSRFConfig) originTrusted(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if "" == origin {
+		return true
+	}
+	if u, err := url.Parse(origin); nil == err && u.Host == r.Host {
+		return true
+	}
+	for _, trusted := range c.TrustedOrigins {
+		if origin == trusted {
+			return true
+		}
+	}
+	return false
+}
+
+func signCSRFPayload(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func generateCSRFToken(secret []byte) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); nil != err {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + signCSRFPayload(secret, payload), nil
+}
+
+func verifyCSRFToken(secrets [][]byte, token string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if 2 != len(parts) {
+		return false
+	}
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(signCSRFPayload(secret, parts[0])), []byte(parts[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func submittedCSRFToken(r *http.Request, config CSRFConfig) string {
+	if token := r.Header.Get(config.headerName()); "" != token {
+		return token
+	}
+	return r.FormValue(config.fieldName())
+}
+
+func isSafeCSRFMethod(method string) bool {
+	return http.MethodGet == method || http.MethodHead == method || http.MethodOptions == method
+}
+
+type csrfContextKey struct{}
+
+// CSRFTokenFromRequest returns the current request's CSRF token, for
+// handlers that need to embed it in a template or a JSON response body so
+// the client can echo it back on its next unsafe-method request. It
+// returns "" outside of a CSRF-wrapped handler, and also for unsafe-method
+// requests, on which the middleware only checks the submitted token.
+func CSRFTokenFromRequest(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey{}).(string)
+	return token
+}
+
+// CSRF wraps h with synchronizer-token CSRF protection (see CSRFConfig):
+// safe methods (GET, HEAD, OPTIONS) are issued a signed token cookie, and
+// unsafe methods must echo that same token back via header or form field or
+// be rejected with a CSRFError. Since Marshaled returns an http.Handler,
+// CSRF(tigertonic.Marshaled(fn), config) protects a Marshaled handler
+// directly; CSRFHandlerFunc is the equivalent for a plain HandlerFunc.
+func CSRF(h http.Handler, config CSRFConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeCSRFMethod(r.Method) {
+			token := ""
+			if cookie, err := r.Cookie(config.cookieName()); nil == err && verifyCSRFToken(config.Secrets, cookie.Value) {
+				token = cookie.Value
+			} else {
+				fresh, genErr := generateCSRFToken(config.signingSecret())
+				if nil != genErr {
+					WriteJSONError(w, NewJSONError("could not generate CSRF token"))
+					return
+				}
+				token = fresh
+				http.SetCookie(w, config.cookie(token))
+			}
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, token)))
+			return
+		}
+
+		if !config.originTrusted(r) {
+			WriteJSONError(w, newCSRFError("request Origin is not an allowed cross-origin caller"))
+			return
+		}
+
+		cookie, err := r.Cookie(config.cookieName())
+		if nil != err {
+			WriteJSONError(w, newCSRFError("missing CSRF cookie"))
+			return
+		}
+
+		submitted := submittedCSRFToken(r, config)
+		if "" == submitted || !hmac.Equal([]byte(cookie.Value), []byte(submitted)) || !verifyCSRFToken(config.Secrets, submitted) {
+			WriteJSONError(w, newCSRFError("CSRF token missing or invalid"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// CSRFHandlerFunc is CSRF for a HandlerFunc instead of an http.Handler.
+func CSRFHandlerFunc(h HandlerFunc, config CSRFConfig) HandlerFunc {
+	wrapped := CSRF(http.HandlerFunc(h), config)
+	return func(w http.ResponseWriter, r *http.Request) {
+		wrapped.ServeHTTP(w, r)
+	}
+}
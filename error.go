@@ -2,10 +2,13 @@ package tigertonic
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"log"
 	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -64,11 +67,108 @@ func NewMethodNotAllowed(desc string) error {
 }
 
 func acceptJSON(r *http.Request) bool {
-	accept := r.Header.Get("Accept")
-	if "" == accept {
+	switch negotiate(
+		r.Header.Get("Accept"),
+		"application/problem+json",
+		"application/json",
+		"application/problem+xml",
+		"application/xml",
+		"text/plain",
+	) {
+	case "application/problem+json", "application/json":
 		return true
 	}
-	return strings.Contains(accept, "*/*") || strings.Contains(accept, "application/json")
+	return false
+}
+
+// mediaRange is one entry parsed out of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (m mediaRange) matches(offer string) bool {
+	parts := strings.SplitN(offer, "/", 2)
+	if 2 != len(parts) {
+		return false
+	}
+	return ("*" == m.typ || m.typ == parts[0]) && ("*" == m.subtype || m.subtype == parts[1])
+}
+
+// specificity ranks "type/subtype" above "type/*" above "*/*", per RFC 7231
+// §5.3.2, so that an exact match wins over a wildcard at the same q-value.
+func (m mediaRange) specificity() int {
+	switch {
+	case "*" == m.typ:
+		return 0
+	case "*" == m.subtype:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func parseAccept(header string) []mediaRange {
+	if "" == header {
+		return nil
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		typ := strings.TrimSpace(fields[0])
+		typeParts := strings.SplitN(typ, "/", 2)
+		if 2 != len(typeParts) {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if 2 == len(kv) && "q" == strings.TrimSpace(kv[0]) {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); nil == err {
+					q = parsed
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{
+			typ:     strings.TrimSpace(typeParts[0]),
+			subtype: strings.TrimSpace(typeParts[1]),
+			q:       q,
+		})
+	}
+	return ranges
+}
+
+// negotiate returns whichever of offers, given in preference order, best
+// satisfies accept, honoring q-values and "type/*"/"*/*" wildcards. A
+// missing or empty Accept header accepts anything, so the first offer wins.
+// It returns "" if none of the offers are acceptable.
+func negotiate(accept string, offers ...string) string {
+	ranges := parseAccept(accept)
+	if nil == ranges {
+		if 0 < len(offers) {
+			return offers[0]
+		}
+		return ""
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	for _, mr := range ranges {
+		for _, offer := range offers {
+			if mr.matches(offer) {
+				return offer
+			}
+		}
+	}
+	return ""
 }
 
 func errorName(err error, fallback string) string {
@@ -114,16 +214,8 @@ func errorStatusCode(err error) int {
 	return http.StatusInternalServerError
 }
 
-// BadField is an error type containing a field name and associated error.
-// This is the type returned from Validate.
-type BadField struct {
-	Field string `json:"field"`
-	Desc  string `json:"description"`
-}
-
-func (b BadField) Error() string {
-	return fmt.Sprintf("field %s is invalid: %v", b.Field, b.Desc)
-}
+// BadField is declared in validate.go, alongside V.Validate, which is what
+// actually produces it.
 
 type ValidationErrorWrapper struct {
 	AppError
@@ -204,3 +296,198 @@ func WritePlaintextError(w http.ResponseWriter, err error) {
 	w.WriteHeader(errorStatusCode(err))
 	fmt.Fprintf(w, "%s: %s", errorName(err, "error"), err)
 }
+
+// problemTypeBase is prepended to an AppError's Type (or an error's
+// errorName) to build the "type" member of a ProblemDetails, since RFC 7807
+// requires it to be a URI reference rather than a bare token.
+const problemTypeBase = "https://github.com/rcrowley/go-tigertonic/problems/"
+
+// ProblemDetails is the RFC 7807 (https://tools.ietf.org/html/rfc7807)
+// "problem detail" object. WriteError serializes it as
+// application/problem+json or application/problem+xml depending on what the
+// request's Accept header negotiates to. Extensions holds any additional,
+// application-specific members, such as "invalid-params" for validation
+// errors; it is merged into the JSON object but dropped from the XML
+// representation, which RFC 7807 does not define an extension mechanism for.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, 5+len(p.Extensions))
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if "" != p.Type {
+		m["type"] = p.Type
+	}
+	if "" != p.Title {
+		m["title"] = p.Title
+	}
+	if 0 != p.Status {
+		m["status"] = p.Status
+	}
+	if "" != p.Detail {
+		m["detail"] = p.Detail
+	}
+	if "" != p.Instance {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+type problemDetailsXML struct {
+	XMLName  xml.Name `xml:"urn:ietf:rfc:7807 problem"`
+	Type     string   `xml:"type,omitempty"`
+	Title    string   `xml:"title,omitempty"`
+	Status   int      `xml:"status,omitempty"`
+	Detail   string   `xml:"detail,omitempty"`
+	Instance string   `xml:"instance,omitempty"`
+}
+
+func (p ProblemDetails) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	return e.Encode(problemDetailsXML{
+		Type:     p.Type,
+		Title:    p.Title,
+		Status:   p.Status,
+		Detail:   p.Detail,
+		Instance: p.Instance,
+	})
+}
+
+// Problem converts e into an RFC 7807 ProblemDetails, using Type as the
+// trailing path segment of the problem type URI and HttpStatusCode as both
+// Status and the basis for Title.
+func (e AppError) Problem() ProblemDetails {
+	typ := e.Type
+	if "" == typ {
+		typ = UnknownErrorType
+	}
+	status := e.HttpStatusCode
+	if 0 == status {
+		status = http.StatusInternalServerError
+	}
+	return ProblemDetails{
+		Type:   problemTypeBase + typ,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: e.Desc,
+	}
+}
+
+// Problem converts v into an RFC 7807 ProblemDetails whose "invalid-params"
+// extension lists each BadField by name and description, plus index/key/path
+// when BadField has them, so a nested slice/map element failure (e.g.
+// "orders/2/lineItems/0/sku") isn't flattened down to a bare field name.
+func (v ValidationErrorWrapper) Problem() ProblemDetails {
+	p := v.AppError.Problem()
+	p.Type = problemTypeBase + "invalid-params"
+
+	invalidParams := make([]map[string]interface{}, 0, len(v.Fields))
+	for _, err := range v.Fields {
+		if bf, ok := err.(BadField); ok {
+			param := map[string]interface{}{
+				"name":   bf.Field,
+				"reason": bf.Desc,
+			}
+			if nil != bf.Index {
+				param["index"] = *bf.Index
+			}
+			if "" != bf.Key {
+				param["key"] = bf.Key
+			}
+			if "" != bf.Path {
+				param["path"] = bf.Path
+			}
+			invalidParams = append(invalidParams, param)
+			continue
+		}
+		invalidParams = append(invalidParams, map[string]interface{}{"reason": err.Error()})
+	}
+	p.Extensions = map[string]interface{}{"invalid-params": invalidParams}
+
+	return p
+}
+
+// problemer is implemented by errors that know how to describe themselves
+// as an RFC 7807 problem. AppError and ValidationErrorWrapper satisfy it.
+type problemer interface {
+	Problem() ProblemDetails
+}
+
+// problemOf converts any error into a ProblemDetails, preferring a Problem()
+// method when err implements one and falling back to errorName/
+// errorStatusCode otherwise.
+func problemOf(err error) ProblemDetails {
+	if p, ok := err.(problemer); ok {
+		return p.Problem()
+	}
+	status := errorStatusCode(err)
+	return ProblemDetails{
+		Type:   problemTypeBase + errorName(err, "error"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}
+}
+
+// WriteError writes err to w using whichever representation r's Accept
+// header negotiates to, trying application/problem+json,
+// application/problem+xml, application/json, application/xml, and
+// text/plain in that preference order. It is the content-negotiating
+// counterpart to WriteJSONError and WritePlaintextError, which remain
+// available for handlers that want a specific representation regardless of
+// the request's Accept header.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch negotiate(
+		r.Header.Get("Accept"),
+		"application/problem+json",
+		"application/problem+xml",
+		"application/json",
+		"application/xml",
+		"text/plain",
+	) {
+	case "application/problem+json":
+		writeProblemJSON(w, err)
+	case "application/problem+xml":
+		writeProblemXML(w, err)
+	case "application/xml":
+		writeXMLError(w, err)
+	case "text/plain":
+		WritePlaintextError(w, err)
+	default:
+		WriteJSONError(w, err)
+	}
+}
+
+func writeProblemJSON(w http.ResponseWriter, err error) {
+	p := problemOf(err)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	if jsonErr := json.NewEncoder(w).Encode(p); nil != jsonErr {
+		log.Printf("Error marshalling problem+json error response: %s", jsonErr)
+	}
+}
+
+func writeProblemXML(w http.ResponseWriter, err error) {
+	p := problemOf(err)
+	w.Header().Set("Content-Type", "application/problem+xml")
+	w.WriteHeader(p.Status)
+	if xmlErr := xml.NewEncoder(w).Encode(p); nil != xmlErr {
+		log.Printf("Error marshalling problem+xml error response: %s", xmlErr)
+	}
+}
+
+func writeXMLError(w http.ResponseWriter, err error) {
+	p := problemOf(err)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(p.Status)
+	if xmlErr := xml.NewEncoder(w).Encode(p); nil != xmlErr {
+		log.Printf("Error marshalling xml error response: %s", xmlErr)
+	}
+}
@@ -0,0 +1,86 @@
+package tigertonic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiatePrefersHighestQValue(t *testing.T) {
+	got := negotiate("application/json;q=0.5, application/problem+json;q=0.9", "application/problem+json", "application/json")
+	if "application/problem+json" != got {
+		t.Fatalf("expected application/problem+json, got %q", got)
+	}
+}
+
+func TestNegotiatePrefersExplicitTypeOverLowerQWildcard(t *testing.T) {
+	got := negotiate("text/plain;q=0.9, */*;q=0.1", "application/problem+json", "text/plain")
+	if "text/plain" != got {
+		t.Fatalf("expected text/plain, got %q", got)
+	}
+}
+
+func TestNegotiateEmptyAcceptTakesFirstOffer(t *testing.T) {
+	got := negotiate("", "application/problem+json", "application/json")
+	if "application/problem+json" != got {
+		t.Fatalf("expected first offer, got %q", got)
+	}
+}
+
+func TestNegotiateReturnsEmptyWhenNothingMatches(t *testing.T) {
+	got := negotiate("text/html", "application/problem+json", "application/json")
+	if "" != got {
+		t.Fatalf("expected no match, got %q", got)
+	}
+}
+
+func TestWriteErrorEmitsProblemJSONWhenRequested(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+
+	WriteError(w, r, &AppError{Type: "widget", Desc: "bad widget", HttpStatusCode: http.StatusBadRequest})
+
+	if "application/problem+json" != w.Header().Get("Content-Type") {
+		t.Fatalf("expected problem+json content type, got %q", w.Header().Get("Content-Type"))
+	}
+	if http.StatusBadRequest != w.Code {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestValidationErrorWrapperProblemListsInvalidParams(t *testing.T) {
+	wrapper := ValidationErrorWrapper{
+		AppError: AppError{Type: ValidationErrorType, HttpStatusCode: http.StatusBadRequest, Desc: "bad"},
+		Fields:   []error{BadField{Field: "name", Desc: "is required"}},
+	}
+	p := wrapper.Problem()
+	params, ok := p.Extensions["invalid-params"].([]map[string]interface{})
+	if !ok || 1 != len(params) {
+		t.Fatalf("expected one invalid-params entry, got %#v", p.Extensions["invalid-params"])
+	}
+	if "name" != params[0]["name"] {
+		t.Fatalf("expected field name %q, got %#v", "name", params[0])
+	}
+}
+
+func TestValidationErrorWrapperProblemKeepsNestedLocation(t *testing.T) {
+	index := 0
+	wrapper := ValidationErrorWrapper{
+		AppError: AppError{Type: ValidationErrorType, HttpStatusCode: http.StatusBadRequest, Desc: "bad"},
+		Fields: []error{BadField{
+			Field: "sku",
+			Desc:  "is required",
+			Index: &index,
+			Path:  "orders/2/lineItems/0/sku",
+		}},
+	}
+	p := wrapper.Problem()
+	params := p.Extensions["invalid-params"].([]map[string]interface{})
+	if "orders/2/lineItems/0/sku" != params[0]["path"] {
+		t.Fatalf("expected path to survive into the problem, got %#v", params[0])
+	}
+	if 0 != params[0]["index"] {
+		t.Fatalf("expected index to survive into the problem, got %#v", params[0])
+	}
+}
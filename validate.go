@@ -30,6 +30,15 @@ and should return an error when the value is deemed invalid.
 
 There is a reserved tag, "struct", which can be used to automatically validate a
 struct field, either named or embedded. This may be combined with user-defined validators.
+A slice, array, or map field is walked the same way, either because its element
+type is itself a struct or because it carries "struct" or the "dive" tag.
+
+Tag entries may also carry an argument after an "=", such as
+`validate:"len=3..64,regex=^[a-z]+$,oneof=red|green|blue"`. Argument-aware and
+cross-field validators (e.g. "eqfield=Password", which compares against a
+sibling field) are looked up in the package-level Validators registry rather
+than in a V's plain map, which only ever sees the field's own value. See
+ValidatorFunc and Validators.
 
 Reflection is used to access the tags and fields, so the usual caveats and limitations apply.
 */
@@ -38,6 +47,7 @@ package tigertonic
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -55,13 +65,27 @@ func SetValidationError(code int, desc string) {
 	ErrorValidation = desc
 }
 
+// StopOnFirstSliceError, when true, makes Validate stop validating the
+// elements of a slice, array, or map field as soon as one element fails,
+// instead of walking every element. It defaults to false, matching
+// Validate's historical behavior of reporting every bad field it finds.
+var StopOnFirstSliceError bool
+
 // BadField is an error type containing a field name and associated error.
 // This is the type returned from Validate.
+//
+// Index and Key are only set when the field that failed validation is an
+// element of a slice/array (Index) or a map (Key) reached while diving into
+// a collection field; Path carries the full JSON-pointer-style location of
+// the failure, e.g. "orders/2/lineItems/0/sku".
 type BadField struct {
 	ErrorString string `json:"error"`
 	ErrorCode   int    `json:"errorCode"`
 	Field       string `json:"field"`
 	Desc        string `json:"description"`
+	Index       *int   `json:"index,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Path        string `json:"path,omitempty"`
 }
 
 func (b BadField) Error() string {
@@ -74,6 +98,13 @@ func (b BadField) Error() string {
 //
 // Fields that are not tagged or cannot be interfaced via reflection
 // are skipped.
+//
+// A field tagged "struct" or "dive" is walked recursively, as is any
+// slice/array/map field whose element type is itself a struct, even without
+// an explicit tag. Each element that fails validation is reported through
+// its own BadField with Index, Key, and Path set to pinpoint exactly which
+// element failed, rather than collapsing the whole collection into one
+// error.
 func (v V) Validate(s interface{}) []error {
 	var val reflect.Value
 
@@ -88,6 +119,10 @@ func (v V) Validate(s interface{}) []error {
 		val = val.Elem()
 	}
 
+	return v.validateStruct(val, "")
+}
+
+func (v V) validateStruct(val reflect.Value, path string) []error {
 	t := val.Type()
 	if t == nil || t.Kind() != reflect.Struct {
 		return nil
@@ -101,46 +136,166 @@ func (v V) Validate(s interface{}) []error {
 		if !fv.CanInterface() {
 			continue
 		}
-		val := fv.Interface()
 		tag := f.Tag.Get("validate")
+		vts := strings.Split(tag, ",")
+		fieldPath := joinPath(path, fieldName(&f))
+
+		if hasTag(vts, "struct") || hasTag(vts, "dive") || isContainerOfStructs(fv) {
+			errs = append(errs, v.validateNested(fv, fieldPath)...)
+		}
+
 		if tag == "" {
 			continue
 		}
-		vts := strings.Split(tag, ",")
 
 		for _, vt := range vts {
-			if vt == "struct" {
-				errs2 := v.Validate(val)
-				if len(errs2) > 0 {
-					errs = append(errs, errs2...)
-				}
+			if vt == "struct" || vt == "dive" {
 				continue
 			}
+			name, arg := splitTagArg(vt)
+
+			var vf ValidatorFunc
+			if legacy, ok := v[name]; ok {
+				vf = adaptLegacy(legacy)
+			} else if builtin, ok := Validators[name]; ok {
+				vf = builtin
+			}
 
-			vf := v[vt]
 			if vf == nil {
 				errs = append(errs, BadField{
 					ErrorString: ErrorValidation,
 					ErrorCode:   ErrorCodeValidation,
 					Field:       f.Name,
-					Desc:        fmt.Sprintf("undefined validator: %q", vt),
+					Desc:        fmt.Sprintf("undefined validator: %q", name),
+					Path:        fieldPath,
 				})
 				continue
 			}
-			if err := vf(val); err != nil {
-				p := fmt.Sprintf("%s", err)
+			ctx := ValidatorContext{Parent: val, Field: f, Value: fv, Arg: arg}
+			if err := vf(ctx); err != nil {
 				errs = append(errs, BadField{
 					ErrorString: ErrorValidation,
 					ErrorCode:   ErrorCodeValidation,
 					Field:       fieldName(&f),
-					Desc:        p,
+					Desc:        fmt.Sprintf("%s", err),
+					Path:        fieldPath,
 				})
 			}
 		}
 	}
 
 	return errs
-} //
+}
+
+// validateNested dispatches a field reached while diving into a struct,
+// slice/array, or map to the appropriate recursive validator, following
+// pointers and skipping nils cleanly.
+func (v V) validateNested(fv reflect.Value, path string) []error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil
+		}
+		return v.validateNested(fv.Elem(), path)
+	case reflect.Struct:
+		return v.validateStruct(fv, path)
+	case reflect.Slice, reflect.Array:
+		return v.validateElements(fv, path)
+	case reflect.Map:
+		return v.validateMapValues(fv, path)
+	default:
+		return nil
+	}
+}
+
+func (v V) validateElements(fv reflect.Value, path string) []error {
+	var errs []error
+
+	for i := 0; i < fv.Len(); i++ {
+		elemErrs := v.validateNested(fv.Index(i), fmt.Sprintf("%s/%d", path, i))
+		for _, err := range elemErrs {
+			if bf, ok := err.(BadField); ok && nil == bf.Index {
+				index := i
+				bf.Index = &index
+				err = bf
+			}
+			errs = append(errs, err)
+		}
+		if 0 < len(elemErrs) && StopOnFirstSliceError {
+			break
+		}
+	}
+
+	return errs
+}
+
+func (v V) validateMapValues(fv reflect.Value, path string) []error {
+	var errs []error
+
+	keys := fv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%v", keys[i].Interface()) < fmt.Sprintf("%v", keys[j].Interface())
+	})
+
+	for _, k := range keys {
+		key := fmt.Sprintf("%v", k.Interface())
+		elemErrs := v.validateNested(fv.MapIndex(k), fmt.Sprintf("%s/%s", path, key))
+		for _, err := range elemErrs {
+			if bf, ok := err.(BadField); ok && "" == bf.Key {
+				bf.Key = key
+				err = bf
+			}
+			errs = append(errs, err)
+		}
+		if 0 < len(elemErrs) && StopOnFirstSliceError {
+			break
+		}
+	}
+
+	return errs
+}
+
+// isContainerOfStructs reports whether fv is a slice, array, or map whose
+// element type is a struct, or a pointer to one, so that such fields are
+// walked recursively even without an explicit "struct"/"dive" tag.
+func isContainerOfStructs(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		t := fv.Type().Elem()
+		for reflect.Ptr == t.Kind() {
+			t = t.Elem()
+		}
+		return reflect.Struct == t.Kind()
+	default:
+		return false
+	}
+}
+
+// splitTagArg splits a single validate tag entry, such as "len=3..64" or
+// "required", into its validator name and argument. Entries with no "="
+// have an empty argument.
+func splitTagArg(vt string) (name, arg string) {
+	if idx := strings.Index(vt, "="); idx >= 0 {
+		return vt[:idx], vt[idx+1:]
+	}
+	return vt, ""
+}
+
+func hasTag(vts []string, name string) bool {
+	for _, vt := range vts {
+		if vt == name {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(prefix, name string) string {
+	if "" == prefix {
+		return name
+	}
+	return prefix + "/" + name
+}
 
 func fieldName(f *reflect.StructField) string {
 	jsonTag := f.Tag.Get("json")
@@ -0,0 +1,70 @@
+package tigertonic
+
+import "testing"
+
+type lineItem struct {
+	SKU string `json:"sku" validate:"required"`
+}
+
+type order struct {
+	LineItems []lineItem `json:"lineItems"`
+}
+
+type root struct {
+	Orders []order `json:"orders"`
+}
+
+func TestValidateRecursesIntoUntaggedNestedSlices(t *testing.T) {
+	v := make(V)
+	s := root{Orders: []order{
+		{LineItems: []lineItem{{SKU: "widget"}, {SKU: ""}}},
+	}}
+
+	errs := v.Validate(s)
+	if 1 != len(errs) {
+		t.Fatalf("expected exactly one error for the empty SKU, got %d: %v", len(errs), errs)
+	}
+
+	bf, ok := errs[0].(BadField)
+	if !ok {
+		t.Fatalf("expected a BadField, got %T", errs[0])
+	}
+	if "orders/0/lineItems/1/sku" != bf.Path {
+		t.Fatalf("expected path orders/0/lineItems/1/sku, got %q", bf.Path)
+	}
+	if nil == bf.Index || 1 != *bf.Index {
+		t.Fatalf("expected Index 1, got %v", bf.Index)
+	}
+}
+
+func TestValidateStopsOnFirstSliceErrorWhenConfigured(t *testing.T) {
+	StopOnFirstSliceError = true
+	defer func() { StopOnFirstSliceError = false }()
+
+	v := make(V)
+	s := root{Orders: []order{
+		{LineItems: []lineItem{{SKU: ""}, {SKU: ""}}},
+	}}
+
+	errs := v.Validate(s)
+	if 1 != len(errs) {
+		t.Fatalf("expected exactly one error with StopOnFirstSliceError, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestValidateReportsMapKey(t *testing.T) {
+	type bag struct {
+		Items map[string]lineItem
+	}
+	v := make(V)
+	s := bag{Items: map[string]lineItem{"a": {SKU: "ok"}, "b": {SKU: ""}}}
+
+	errs := v.Validate(s)
+	if 1 != len(errs) {
+		t.Fatalf("expected one error, got %d: %v", len(errs), errs)
+	}
+	bf := errs[0].(BadField)
+	if "b" != bf.Key {
+		t.Fatalf("expected key %q, got %q", "b", bf.Key)
+	}
+}
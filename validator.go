@@ -0,0 +1,284 @@
+package tigertonic
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidatorContext carries everything a ValidatorFunc needs to validate a
+// single tagged field: its Value, the Arg parsed out of the tag entry
+// (e.g. "3..64" in "len=3..64", "" when the entry had no "="), and enough
+// of the surrounding struct (Parent, Field) to support cross-field rules
+// such as "eqfield=Password".
+type ValidatorContext struct {
+	Parent reflect.Value
+	Field  reflect.StructField
+	Value  reflect.Value
+	Arg    string
+}
+
+// ValidatorFunc validates the field described by ctx, returning a non-nil
+// error when it is invalid.
+type ValidatorFunc func(ctx ValidatorContext) error
+
+// Registry maps validate tag names to the ValidatorFuncs that implement
+// them.
+type Registry map[string]ValidatorFunc
+
+// Validators is the package-level registry consulted for any tag name not
+// found in the V passed to Validate. It comes pre-populated with the
+// built-ins below; register more by assigning into it, e.g.
+// Validators["even"] = func(ctx ValidatorContext) error { … }.
+var Validators = Registry{
+	"required": requiredValidator,
+	"len":      lenValidator,
+	"min":      minValidator,
+	"max":      maxValidator,
+	"regex":    regexValidator,
+	"email":    emailValidator,
+	"url":      urlValidator,
+	"uuid":     uuidValidator,
+	"oneof":    oneofValidator,
+	"eqfield":  eqfieldValidator,
+	"gtfield":  gtfieldValidator,
+	"ltfield":  ltfieldValidator,
+}
+
+// adaptLegacy wraps the historical V validator signature, func(interface{})
+// error, as a ValidatorFunc so Validate can call either kind of validator
+// uniformly. Legacy validators ignore Arg and sibling fields; they only
+// ever see ctx.Value.
+func adaptLegacy(fn func(interface{}) error) ValidatorFunc {
+	return func(ctx ValidatorContext) error {
+		return fn(ctx.Value.Interface())
+	}
+}
+
+var (
+	emailRegexp = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	uuidRegexp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+func requiredValidator(ctx ValidatorContext) error {
+	if !ctx.Value.IsValid() || reflect.DeepEqual(ctx.Value.Interface(), reflect.Zero(ctx.Value.Type()).Interface()) {
+		return fmt.Errorf("is required")
+	}
+	return nil
+}
+
+func collectionLen(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+// parseRange parses "min..max", "min.." (unbounded above), or a bare "n"
+// (meaning exactly n) as used by the "len" validator's argument.
+func parseRange(arg string) (min, max int, err error) {
+	parts := strings.SplitN(arg, "..", 2)
+	if min, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %s", arg, err)
+	}
+	if 1 == len(parts) {
+		return min, min, nil
+	}
+	if "" == strings.TrimSpace(parts[1]) {
+		return min, -1, nil
+	}
+	if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %s", arg, err)
+	}
+	return min, max, nil
+}
+
+func lenValidator(ctx ValidatorContext) error {
+	n, ok := collectionLen(ctx.Value)
+	if !ok {
+		return fmt.Errorf("len validator does not support %s", ctx.Value.Kind())
+	}
+	min, max, err := parseRange(ctx.Arg)
+	if err != nil {
+		return err
+	}
+	if n < min || (-1 != max && n > max) {
+		return fmt.Errorf("length %d is out of range %s", n, ctx.Arg)
+	}
+	return nil
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func minValidator(ctx ValidatorContext) error {
+	n, ok := numericValue(ctx.Value)
+	if !ok {
+		return fmt.Errorf("min validator does not support %s", ctx.Value.Kind())
+	}
+	min, err := strconv.ParseFloat(ctx.Arg, 64)
+	if err != nil {
+		return fmt.Errorf("invalid min argument %q: %s", ctx.Arg, err)
+	}
+	if n < min {
+		return fmt.Errorf("%v is less than minimum %v", n, min)
+	}
+	return nil
+}
+
+func maxValidator(ctx ValidatorContext) error {
+	n, ok := numericValue(ctx.Value)
+	if !ok {
+		return fmt.Errorf("max validator does not support %s", ctx.Value.Kind())
+	}
+	max, err := strconv.ParseFloat(ctx.Arg, 64)
+	if err != nil {
+		return fmt.Errorf("invalid max argument %q: %s", ctx.Arg, err)
+	}
+	if n > max {
+		return fmt.Errorf("%v is greater than maximum %v", n, max)
+	}
+	return nil
+}
+
+func regexValidator(ctx ValidatorContext) error {
+	if reflect.String != ctx.Value.Kind() {
+		return fmt.Errorf("regex validator does not support %s", ctx.Value.Kind())
+	}
+	re, err := regexp.Compile(ctx.Arg)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %s", ctx.Arg, err)
+	}
+	if !re.MatchString(ctx.Value.String()) {
+		return fmt.Errorf("%q does not match %s", ctx.Value.String(), ctx.Arg)
+	}
+	return nil
+}
+
+func emailValidator(ctx ValidatorContext) error {
+	if reflect.String != ctx.Value.Kind() {
+		return fmt.Errorf("email validator does not support %s", ctx.Value.Kind())
+	}
+	if !emailRegexp.MatchString(ctx.Value.String()) {
+		return fmt.Errorf("%q is not a valid email address", ctx.Value.String())
+	}
+	return nil
+}
+
+func urlValidator(ctx ValidatorContext) error {
+	if reflect.String != ctx.Value.Kind() {
+		return fmt.Errorf("url validator does not support %s", ctx.Value.Kind())
+	}
+	u, err := url.Parse(ctx.Value.String())
+	if err != nil || "" == u.Scheme || "" == u.Host {
+		return fmt.Errorf("%q is not a valid URL", ctx.Value.String())
+	}
+	return nil
+}
+
+func uuidValidator(ctx ValidatorContext) error {
+	if reflect.String != ctx.Value.Kind() {
+		return fmt.Errorf("uuid validator does not support %s", ctx.Value.Kind())
+	}
+	if !uuidRegexp.MatchString(ctx.Value.String()) {
+		return fmt.Errorf("%q is not a valid uuid", ctx.Value.String())
+	}
+	return nil
+}
+
+func oneofValidator(ctx ValidatorContext) error {
+	if reflect.String != ctx.Value.Kind() {
+		return fmt.Errorf("oneof validator does not support %s", ctx.Value.Kind())
+	}
+	s := ctx.Value.String()
+	for _, opt := range strings.Split(ctx.Arg, "|") {
+		if s == opt {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", s, ctx.Arg)
+}
+
+// siblingField looks up ctx.Arg as a field name on ctx.Parent, for the
+// cross-field validators below.
+func siblingField(ctx ValidatorContext) (reflect.Value, error) {
+	if reflect.Struct != ctx.Parent.Kind() {
+		return reflect.Value{}, fmt.Errorf("no parent struct to find field %q on", ctx.Arg)
+	}
+	sibling := ctx.Parent.FieldByName(ctx.Arg)
+	if !sibling.IsValid() {
+		return reflect.Value{}, fmt.Errorf("no sibling field named %q", ctx.Arg)
+	}
+	return sibling, nil
+}
+
+func eqfieldValidator(ctx ValidatorContext) error {
+	sibling, err := siblingField(ctx)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(ctx.Value.Interface(), sibling.Interface()) {
+		return fmt.Errorf("must equal field %s", ctx.Arg)
+	}
+	return nil
+}
+
+// compareToField backs gtfield/ltfield: it looks up the sibling field named
+// by ctx.Arg and calls less(ctx.Value, sibling), the caller's comparison,
+// which reports whether the two kinds can be compared (numerically or
+// lexically) and whether the comparison holds.
+func compareToField(ctx ValidatorContext, less func(a, b reflect.Value) (bool, bool)) error {
+	sibling, err := siblingField(ctx)
+	if err != nil {
+		return err
+	}
+	ok, matches := less(ctx.Value, sibling)
+	if !ok {
+		return fmt.Errorf("cannot compare %s to field %s", ctx.Value.Kind(), ctx.Arg)
+	}
+	if !matches {
+		return fmt.Errorf("comparison against field %s failed", ctx.Arg)
+	}
+	return nil
+}
+
+func gtfieldValidator(ctx ValidatorContext) error {
+	return compareToField(ctx, func(a, b reflect.Value) (bool, bool) {
+		if an, aok := numericValue(a); aok {
+			if bn, bok := numericValue(b); bok {
+				return true, an > bn
+			}
+		}
+		if reflect.String == a.Kind() && reflect.String == b.Kind() {
+			return true, a.String() > b.String()
+		}
+		return false, false
+	})
+}
+
+func ltfieldValidator(ctx ValidatorContext) error {
+	return compareToField(ctx, func(a, b reflect.Value) (bool, bool) {
+		if an, aok := numericValue(a); aok {
+			if bn, bok := numericValue(b); bok {
+				return true, an < bn
+			}
+		}
+		if reflect.String == a.Kind() && reflect.String == b.Kind() {
+			return true, a.String() < b.String()
+		}
+		return false, false
+	})
+}
@@ -0,0 +1,51 @@
+package tigertonic
+
+import (
+	"fmt"
+	"testing"
+)
+
+type signup struct {
+	Username string `json:"username" validate:"len=3..16,regex=^[a-z]+$"`
+	Color    string `json:"color" validate:"oneof=red|green|blue"`
+	Password string `json:"password"`
+	Confirm  string `json:"confirm" validate:"eqfield=Password"`
+}
+
+func TestRegistryValidatorsCatchInvalidFields(t *testing.T) {
+	v := make(V)
+	s := signup{Username: "AB", Color: "purple", Password: "hunter2", Confirm: "hunter3"}
+
+	errs := v.Validate(s)
+	if 4 != len(errs) {
+		t.Fatalf("expected 4 errors, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestRegistryValidatorsPassValidFields(t *testing.T) {
+	v := make(V)
+	s := signup{Username: "abc", Color: "green", Password: "hunter2", Confirm: "hunter2"}
+
+	errs := v.Validate(s)
+	if 0 != len(errs) {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestLegacyValidatorStillWorksAlongsideRegistry(t *testing.T) {
+	type legacy struct {
+		Name string `json:"name" validate:"custom"`
+	}
+	v := make(V)
+	v["custom"] = func(i interface{}) error {
+		if "" == i.(string) {
+			return fmt.Errorf("custom: must not be empty")
+		}
+		return nil
+	}
+
+	errs := v.Validate(legacy{Name: ""})
+	if 1 != len(errs) {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+}